@@ -0,0 +1,140 @@
+// Package cache provides a small on-disk cache of previously seen file
+// metadata, so that a repeat walk of the same root can skip re-analyzing
+// anything whose (size, mtime, mode) stat tuple hasn't changed.
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucketName = []byte("file_info")
+
+// Entry is the cached state for a single path: a fingerprint of its stat
+// tuple plus the file_info string last computed for it.
+type Entry struct {
+	StatHash uint64
+	FileInfo string
+}
+
+// FileEntry is the minimal per-path information ChangeSet needs from a
+// walker in order to compute a stat-tuple fingerprint.
+type FileEntry struct {
+	Path    string
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
+	IsDir   bool
+}
+
+// Cache is a local bbolt-backed store keyed by path, exactly as passed to
+// Lookup and Put; relative paths are not made absolute.
+type Cache struct {
+	db *bolt.DB
+}
+
+// Open opens, creating if necessary, the bbolt cache file at path.
+func Open(path string) (*Cache, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketName)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Cache{db: db}, nil
+}
+
+// Close releases the underlying bbolt file.
+func (c *Cache) Close() error {
+	return c.db.Close()
+}
+
+// Lookup returns the cached Entry for path, if one exists.
+func (c *Cache) Lookup(path string) (Entry, bool) {
+	var entry Entry
+	var found bool
+	c.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucketName).Get([]byte(path))
+		if v == nil {
+			return nil
+		}
+		found = true
+		entry = decodeEntry(v)
+		return nil
+	})
+	return entry, found
+}
+
+// Put stores entry under path, overwriting any previous value.
+func (c *Cache) Put(path string, entry Entry) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Put([]byte(path), encodeEntry(entry))
+	})
+}
+
+// StatHash fingerprints the (size, mtime, mode) stat tuple of e.
+func StatHash(e FileEntry) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d:%d:%s", e.Size, e.Mode, e.ModTime.UTC().Format(time.RFC3339Nano))
+	return h.Sum64()
+}
+
+// ChangeSet runs walker over root and forwards to out only the entries
+// whose stat tuple differs from what is already cached (or that aren't
+// cached at all). It does not update the cache itself - that's left to
+// the caller, which is in the best position to decide what, if anything,
+// to store once it has acted on a changed entry.
+func ChangeSet(ctx context.Context, c *Cache, walker func(root string, out chan<- FileEntry) error, root string, out chan<- FileEntry) error {
+	defer close(out)
+
+	in := make(chan FileEntry)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- walker(root, in)
+	}()
+
+	for e := range in {
+		select {
+		case <-ctx.Done():
+			continue
+		default:
+		}
+
+		cached, ok := c.Lookup(e.Path)
+		if ok && cached.StatHash == StatHash(e) {
+			continue
+		}
+		out <- e
+	}
+
+	return <-errCh
+}
+
+func encodeEntry(e Entry) []byte {
+	buf := make([]byte, 8+len(e.FileInfo))
+	binary.BigEndian.PutUint64(buf[:8], e.StatHash)
+	copy(buf[8:], e.FileInfo)
+	return buf
+}
+
+func decodeEntry(buf []byte) Entry {
+	if len(buf) < 8 {
+		return Entry{}
+	}
+	return Entry{
+		StatHash: binary.BigEndian.Uint64(buf[:8]),
+		FileInfo: string(buf[8:]),
+	}
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStatHashStableAndSensitive(t *testing.T) {
+	base := FileEntry{Path: "/a", Size: 100, Mode: 0644, ModTime: time.Unix(1000, 0)}
+
+	if StatHash(base) != StatHash(base) {
+		t.Fatal("StatHash should be deterministic for the same FileEntry")
+	}
+
+	changedSize := base
+	changedSize.Size = 200
+	if StatHash(changedSize) == StatHash(base) {
+		t.Fatal("StatHash should change when size changes")
+	}
+
+	changedTime := base
+	changedTime.ModTime = time.Unix(2000, 0)
+	if StatHash(changedTime) == StatHash(base) {
+		t.Fatal("StatHash should change when mtime changes")
+	}
+}
+
+func TestPutLookupRoundTrip(t *testing.T) {
+	c, err := Open(t.TempDir() + "/cache.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, ok := c.Lookup("/a"); ok {
+		t.Fatal("Lookup should miss on an empty cache")
+	}
+
+	want := Entry{StatHash: 42, FileInfo: "ASCII text"}
+	if err := c.Put("/a", want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := c.Lookup("/a")
+	if !ok {
+		t.Fatal("Lookup should hit after Put")
+	}
+	if got != want {
+		t.Fatalf("Lookup = %+v, want %+v", got, want)
+	}
+}
+
+// TestChangeSetSkipsUnchanged covers the scenario runIncremental relies
+// on: a second walk over an unmodified file must not be forwarded, but a
+// walk after the file's stat tuple changes must be.
+func TestChangeSetSkipsUnchanged(t *testing.T) {
+	c, err := Open(t.TempDir() + "/cache.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	entry := FileEntry{Path: "/a", Size: 10, Mode: os.FileMode(0644), ModTime: time.Unix(1, 0)}
+	walker := func(entries []FileEntry) func(string, chan<- FileEntry) error {
+		return func(root string, out chan<- FileEntry) error {
+			defer close(out)
+			for _, e := range entries {
+				out <- e
+			}
+			return nil
+		}
+	}
+
+	seen, err := drain(c, walker([]FileEntry{entry}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 1 {
+		t.Fatalf("first pass: got %d entries, want 1", len(seen))
+	}
+	if err := c.Put(entry.Path, Entry{StatHash: StatHash(entry)}); err != nil {
+		t.Fatal(err)
+	}
+
+	seen, err = drain(c, walker([]FileEntry{entry}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 0 {
+		t.Fatalf("unchanged re-walk: got %d entries, want 0", len(seen))
+	}
+
+	changed := entry
+	changed.Size = 20
+	seen, err = drain(c, walker([]FileEntry{changed}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 1 {
+		t.Fatalf("changed re-walk: got %d entries, want 1", len(seen))
+	}
+}
+
+func drain(c *Cache, walker func(string, chan<- FileEntry) error) ([]FileEntry, error) {
+	out := make(chan FileEntry)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ChangeSet(context.Background(), c, walker, "/root", out)
+	}()
+
+	var seen []FileEntry
+	for e := range out {
+		seen = append(seen, e)
+	}
+	return seen, <-errCh
+}
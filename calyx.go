@@ -1,223 +1,619 @@
 package main
 
 import (
-	"database/sql"
+	"bufio"
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
-	"path"
+	"os/signal"
+	"path/filepath"
 	"strings"
 	"sync"
-
+	"time"
+
+	"github.com/gopher/calyx/cache"
+	"github.com/gopher/calyx/storage"
+	_ "github.com/gopher/calyx/storage/jsonl"
+	_ "github.com/gopher/calyx/storage/null"
+	_ "github.com/gopher/calyx/storage/postgres"
+	_ "github.com/gopher/calyx/storage/sqlite"
 	"github.com/gopher/gomagic"
-	_ "github.com/lib/pq"
 )
 
-const numAnalyzers = 1
-const txCommitInterval = 1000
-
 // command line options
 var dbUser string
 var dbName string
 var dbPass string
 var dbHost string
+var numWorkers int
+var numReaders int
+var incremental bool
+var cachePath string
+var backendName string
+var backendPath string
+var stdinMode bool
+var nulSeparated bool
+var hashSpec string
+var maxHashSize int64
+var followSymlinks bool
 
 func init() {
-	flag.StringVar(&dbUser, "u", "", "database user")
-	flag.StringVar(&dbName, "n", "", "database name")
-	flag.StringVar(&dbPass, "p", "", "database password")
-	flag.StringVar(&dbHost, "h", "", "database host")
+	flag.StringVar(&dbUser, "u", "", "database user (postgres backend)")
+	flag.StringVar(&dbName, "n", "", "database name (postgres backend)")
+	flag.StringVar(&dbPass, "p", "", "database password (postgres backend)")
+	flag.StringVar(&dbHost, "h", "", "database host (postgres backend)")
+	flag.IntVar(&numWorkers, "workers", 4, "number of analyzer goroutines")
+	flag.IntVar(&numReaders, "readers", 2, "number of directory reader goroutines")
+	flag.BoolVar(&incremental, "incremental", false, "only re-analyze files whose (size, mtime, mode) changed since the last run against this root")
+	flag.StringVar(&cachePath, "cache", ".calyx-cache.db", "path to the incremental scan cache, used with -incremental")
+	flag.StringVar(&backendName, "backend", "postgres", "storage backend to use: postgres, sqlite, jsonl, null")
+	flag.StringVar(&backendPath, "o", "", "output path for the sqlite/jsonl backends (defaults to calyx.db / stdout)")
+	flag.BoolVar(&stdinMode, "stdin", false, "read paths to analyze from stdin instead of walking a root directory (same as passing - as the root)")
+	flag.BoolVar(&nulSeparated, "0", false, "paths read via -stdin are NUL-separated instead of newline-separated")
+	flag.StringVar(&hashSpec, "hash", "sha256", "comma-separated content hashes to compute: sha1, sha256, blake3 (empty disables hashing)")
+	flag.Int64Var(&maxHashSize, "max-hash-size", 0, "skip hashing files larger than this many bytes (0 means no limit)")
+	flag.BoolVar(&followSymlinks, "follow-symlinks", false, "descend into directories reached via a symlink instead of just recording the link")
 }
 
 // FileEntry captures the POSIX attributes for a filesystem entry (file, dir, link, ...)
 type FileEntry struct {
-	path string
-	info os.FileInfo
+	path    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	isDir   bool
+	// linkTarget is the os.Readlink result for symlink entries, empty otherwise.
+	linkTarget string
+	hashes     map[string]string // algorithm name -> hex digest, populated by hashStage
 }
 
 func main() {
 
 	flag.Parse()
-	if dbUser == "" || dbName == "" || dbPass == "" || dbHost == "" {
+	if backendName == "postgres" && (dbUser == "" || dbName == "" || dbPass == "" || dbHost == "") {
 		Usage()
 	}
 
-	if len(flag.Args()) == 0 {
+	var filePath string
+	if len(flag.Args()) > 0 {
+		filePath = flag.Args()[0]
+	}
+	if filePath == "-" {
+		stdinMode = true
+	}
+	if !stdinMode && filePath == "" {
 		Usage()
 	}
-	filePath := flag.Args()[0]
 
-	var wg sync.WaitGroup
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	if numReaders < 1 {
+		numReaders = 1
+	}
+
+	cfg := storage.Config{DBUser: dbUser, DBName: dbName, DBPass: dbPass, DBHost: dbHost, Path: backendPath}
 
-	db, err := createTable()
+	hashAlgos, err := parseHashAlgorithms(hashSpec)
 	if err != nil {
-		log.Fatal("Failed to create database table with ", err)
+		log.Fatal("invalid -hash: ", err)
 	}
 
-	fileChannel := make(chan FileEntry)
+	// Validate the backend and run its one-time schema setup (e.g. CREATE
+	// TABLE) here, serially, before any analyzer goroutine touches it.
+	// Doing this per-analyzer instead (as analyzer used to) turned a bad
+	// -backend name or an unreachable database into a silent hang - every
+	// worker would fail to start and return without ever draining
+	// fileChannel, while the walker kept blocking on it - and raced
+	// concurrent "IF NOT EXISTS" DDL against the same Postgres catalog
+	// objects from N sessions at once.
+	probe, err := storage.Open(backendName, cfg)
+	if err != nil {
+		log.Fatal("failed to open storage backend: ", err)
+	}
+	if err := probe.Init(); err != nil {
+		log.Fatal("failed to initialize storage backend: ", err)
+	}
+	if err := probe.Close(); err != nil {
+		log.Println("failed to close storage backend probe: ", err)
+	}
+
+	var wg sync.WaitGroup
+
+	// done is closed on Ctrl-C to let in-flight readers and analyzers
+	// unwind instead of leaving the walk half-finished.
+	done := make(chan struct{})
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	go func() {
+		<-sigChan
+		log.Println("interrupted, waiting for in-flight work to finish...")
+		close(done)
+	}()
+
+	// rawChannel carries entries straight from the walker/stdin/cache
+	// source; hashStage enriches them with content digests before handing
+	// them to the analyzer pool on fileChannel.
+	rawChannel := make(chan FileEntry, numReaders*4)
+	fileChannel := make(chan FileEntry, numReaders*4)
+
+	// Under -incremental, the scan cache is only updated once an analyzer
+	// confirms a dispatched entry was actually persisted: incCache and
+	// pending are shared between runIncremental (which records the stat
+	// hash it computed for each path it dispatches) and the commitDrain
+	// goroutine below (which applies it once the corresponding commitAck
+	// arrives).
+	var incCache *cache.Cache
+	var pending *pendingCache
+	var committed chan commitAck
+	commitDone := make(chan struct{})
+	if incremental {
+		var err error
+		incCache, err = cache.Open(cachePath)
+		if err != nil {
+			log.Fatal("failed to open incremental cache ", cachePath, ": ", err)
+		}
+		defer incCache.Close()
+
+		pending = newPendingCache()
+		committed = make(chan commitAck, numReaders*4)
+		go func() {
+			defer close(commitDone)
+			for ack := range committed {
+				hash, ok := pending.take(ack.path)
+				if !ok || !ack.ok {
+					continue
+				}
+				if err := incCache.Put(ack.path, cache.Entry{StatHash: hash, FileInfo: ack.fileInfo}); err != nil {
+					log.Println("failed to update incremental cache for ", ack.path, ": ", err)
+				}
+			}
+		}()
+	}
 
-	for i := 0; i < numAnalyzers; i++ {
+	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
-		go analyzer(fileChannel, db, &wg)
+		go analyzer(fileChannel, cfg, &wg, done, committed)
 	}
 
-	if err := fileTreeWalker(filePath, fileChannel); err != nil {
+	hashStage(rawChannel, fileChannel, numWorkers, hashAlgos, maxHashSize, done)
+
+	switch {
+	case stdinMode:
+		err = readStdinPaths(rawChannel, done)
+	case incremental:
+		err = runIncremental(filePath, rawChannel, done, incCache, pending)
+	default:
+		err = fileTreeWalker(filePath, rawChannel, numReaders, followSymlinks, done)
+	}
+	if err != nil {
 		log.Fatal("file tree walk failed: ", err)
 	}
 
 	wg.Wait()
+	if committed != nil {
+		close(committed)
+		<-commitDone
+	}
 }
 
-// createTable creates the initial database table used for storing the
-// information of the file tree walk
-func createTable() (*sql.DB, error) {
-	authString := fmt.Sprintf("user=%s dbname=%s password=%s host=%s sslmode=disable",
-		dbUser, dbName, dbPass, dbHost)
-	db, err := sql.Open("postgres", authString)
-	if err != nil {
-		return nil, err
-	}
-
-	sqlStmt := `
-	CREATE TABLE IF NOT EXISTS file_info (
-		id SERIAL NOT NULL PRIMARY KEY,
-		name TEXT,
-		size BIGINT,
-		mode BIGINT,
-		time TEXT,
-		extension TEXT,
-		is_dir BOOLEAN,
-		short_file_info TEXT,
-		file_info TEXT);
-	`
-	_, err = db.Exec(sqlStmt)
-	if err != nil {
-		return nil, err
+// runIncremental feeds fileChannel only the entries under root whose stat
+// tuple has changed (or that are new) since the last run recorded in c,
+// closing fileChannel once the walk and cache comparison are done. Each
+// dispatched entry's stat hash is recorded in pending, keyed by path, so
+// the cache can be updated once the corresponding analyzer reports the
+// entry as durably committed - not the moment it's handed off here.
+func runIncremental(root string, fileChannel chan<- FileEntry, done <-chan struct{}, c *cache.Cache, pending *pendingCache) error {
+	changed := make(chan cache.FileEntry, numReaders*4)
+	walkErr := make(chan error, 1)
+	go func() {
+		walkErr <- cache.ChangeSet(context.Background(), c, walkToCache(numReaders, done), root, changed)
+	}()
+
+	for e := range changed {
+		linkTarget := ""
+		if e.Mode&os.ModeSymlink != 0 {
+			linkTarget, _ = os.Readlink(e.Path)
+		}
+		pending.put(e.Path, cache.StatHash(e))
+		// Once done is closed, keep draining changed without forwarding
+		// so the walk can unwind instead of blocking on fileChannel.
+		select {
+		case fileChannel <- FileEntry{
+			path:       e.Path,
+			size:       e.Size,
+			mode:       e.Mode,
+			modTime:    e.ModTime,
+			isDir:      e.IsDir,
+			linkTarget: linkTarget,
+		}:
+		case <-done:
+		}
 	}
-	return db, nil
+	close(fileChannel)
+
+	return <-walkErr
+}
+
+// commitAck is sent by an analyzer once it has attempted to write a
+// FileEntry's analysis to the storage backend, so -incremental can mark
+// the entry seen in its cache only after that happens rather than at
+// dispatch time. ok reports whether the Insert actually succeeded; a
+// failed attempt still needs to be acked so the entry's pendingCache
+// bookkeeping gets cleaned up instead of leaking.
+type commitAck struct {
+	path     string
+	fileInfo string
+	ok       bool
 }
 
-// fileTreeWalker walks the POSIX file tree under root and sends the
-// paths to all file objects underneath to the file channel
-// TODO: This version should be replaced by a multithreaded version
-// for efficiency
-func fileTreeWalker(rootPath string, files chan<- FileEntry) error {
+// pendingCache tracks the stat hash computed for each path that has been
+// dispatched to the analyzer pool but not yet confirmed committed, so it
+// can be recovered by path when the matching commitAck arrives.
+type pendingCache struct {
+	mu    sync.Mutex
+	stats map[string]uint64
+}
+
+func newPendingCache() *pendingCache {
+	return &pendingCache{stats: map[string]uint64{}}
+}
+
+func (p *pendingCache) put(path string, hash uint64) {
+	p.mu.Lock()
+	p.stats[path] = hash
+	p.mu.Unlock()
+}
+
+// take returns and forgets the stat hash recorded for path, if any.
+func (p *pendingCache) take(path string) (uint64, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	hash, ok := p.stats[path]
+	delete(p.stats, path)
+	return hash, ok
+}
+
+// walkToCache adapts fileTreeWalker to the cache.ChangeSet walker shape,
+// translating each FileEntry it produces into a cache.FileEntry.
+func walkToCache(numReaders int, done <-chan struct{}) func(string, chan<- cache.FileEntry) error {
+	return func(root string, out chan<- cache.FileEntry) error {
+		raw := make(chan FileEntry, numReaders*4)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- fileTreeWalker(root, raw, numReaders, followSymlinks, done)
+		}()
+		for e := range raw {
+			out <- cache.FileEntry{
+				Path:    e.path,
+				Size:    e.size,
+				Mode:    e.mode,
+				ModTime: e.modTime,
+				IsDir:   e.isDir,
+			}
+		}
+		close(out)
+		return <-errCh
+	}
+}
+
+// readStdinPaths reads newline- (or, with -0, NUL-) separated absolute
+// paths from stdin and feeds the corresponding FileEntry for each straight
+// into files, without walking a root directory. Paths that no longer
+// exist are logged and skipped. This makes calyx composable with tools
+// like find, git ls-files, fd, or rsync --list-only.
+//
+// Unlike a plain Scan loop, the actual reading happens in a background
+// goroutine so the main loop can select on done as well: a blocked stdin
+// read (e.g. a FIFO whose writer never closes it) would otherwise ignore
+// Ctrl-C entirely, the way every other entry point already respects it.
+func readStdinPaths(files chan<- FileEntry, done <-chan struct{}) error {
 	defer close(files)
 
-	info, err := os.Stat(rootPath)
+	type line struct {
+		path string
+		err  error
+	}
+	lines := make(chan line)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		if nulSeparated {
+			scanner.Split(splitNUL)
+		}
+		for scanner.Scan() {
+			select {
+			case lines <- line{path: scanner.Text()}:
+			case <-done:
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			select {
+			case lines <- line{err: err}:
+			case <-done:
+			}
+		}
+	}()
+
+	for {
+		select {
+		case l, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			if l.err != nil {
+				return l.err
+			}
+			if l.path == "" {
+				continue
+			}
+			info, err := os.Lstat(l.path)
+			if err != nil {
+				log.Println("readStdinPaths: skipping ", l.path, ": ", err)
+				continue
+			}
+			linkTarget := ""
+			if info.Mode()&os.ModeSymlink != 0 {
+				linkTarget, _ = os.Readlink(l.path)
+			}
+			entry := FileEntry{path: l.path, size: info.Size(), mode: info.Mode(), modTime: info.ModTime(), isDir: info.IsDir(), linkTarget: linkTarget}
+			select {
+			case files <- entry:
+			case <-done:
+				return nil
+			}
+		case <-done:
+			return nil
+		}
+	}
+}
+
+// splitNUL is a bufio.SplitFunc that splits on NUL bytes, for -stdin -0.
+func splitNUL(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// fileTreeWalker walks the POSIX file tree under root with a bounded pool of
+// numReaders directory-reader goroutines, and sends every file object found
+// underneath to the file channel. It returns once every directory reachable
+// from root has been drained, or early if done is closed. Symlinks are
+// recorded as such and not followed, unless followSymlinks is set, in
+// which case a symlinkGuard prevents descending into the same target
+// directory twice.
+func fileTreeWalker(rootPath string, files chan<- FileEntry, numReaders int, followSymlinks bool, done <-chan struct{}) error {
+	info, err := os.Lstat(rootPath)
 	if err != nil {
 		return err
 	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		if !followSymlinks {
+			return fmt.Errorf("root path %s is a symlink; pass -follow-symlinks to traverse it", rootPath)
+		}
+		target, err := os.Stat(rootPath)
+		if err != nil {
+			return err
+		}
+		info = target
+	}
 	if !info.IsDir() {
 		return fmt.Errorf("Provided root path %s is not a directory", rootPath)
 	}
 
-	queue := []string{rootPath}
-	files <- FileEntry{path.Dir(rootPath), info}
-	for len(queue) != 0 {
-		dir := queue[0]
-		queue = queue[1:]
+	guard := newSymlinkGuard()
+	guard.seen(info)
+
+	// dirChannel carries directories still waiting to be read. pending
+	// tracks outstanding directories (queued or being read) so the
+	// channel can be closed exactly once everything has drained, even
+	// though readers themselves are the ones adding more work to it.
+	dirChannel := make(chan string, numReaders*4)
+	var pending sync.WaitGroup
+
+	files <- FileEntry{path: rootPath, size: info.Size(), mode: info.Mode(), modTime: info.ModTime(), isDir: info.IsDir()}
+	pending.Add(1)
+	dirChannel <- rootPath
+
+	var readers sync.WaitGroup
+	for i := 0; i < numReaders; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case dir, ok := <-dirChannel:
+					if !ok {
+						return
+					}
+					readDir(dir, files, dirChannel, &pending, followSymlinks, guard, done)
+					pending.Done()
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		pending.Wait()
+		close(dirChannel)
+	}()
+
+	readers.Wait()
+	close(files)
+	return nil
+}
+
+// readDir lists a single directory, forwards its entries to files and
+// queues any subdirectories it finds (including, with followSymlinks,
+// directories reached through a symlink not already seen via guard) back
+// onto dirChannel for the reader pool to pick up.
+func readDir(dir string, files chan<- FileEntry, dirChannel chan<- string, pending *sync.WaitGroup, followSymlinks bool, guard *symlinkGuard, done <-chan struct{}) {
+	entries, err := os.ReadDir(dir)
+	// we ignore eny errors (such as permission denied, etc.), log them and soldier on
+	if err != nil {
+		log.Println("fileTreeWalker: ", err)
+	}
+	for _, de := range entries {
+		entryPath := filepath.Join(dir, de.Name())
 
-		entries, err := ioutil.ReadDir(dir)
-		// we ignore eny errors (such as permission denied, etc.), log them and soldier on
+		info, err := de.Info()
 		if err != nil {
 			log.Println("fileTreeWalker: ", err)
+			continue
+		}
+
+		linkTarget := ""
+		isSymlink := info.Mode()&os.ModeSymlink != 0
+		if isSymlink {
+			linkTarget, _ = os.Readlink(entryPath)
 		}
-		for _, e := range entries {
-			if e.IsDir() {
-				queue = append(queue, path.Join(dir, e.Name()))
+
+		descend := info.IsDir()
+		if isSymlink && followSymlinks {
+			if target, err := os.Stat(entryPath); err == nil && target.IsDir() && !guard.seen(target) {
+				descend = true
 			}
-			files <- FileEntry{dir, e}
+		}
+
+		if descend {
+			pending.Add(1)
+			// Queue from a separate goroutine: readers are dirChannel's
+			// only consumers, so a reader blocking on a full dirChannel
+			// could leave every reader stuck sending and none receiving.
+			go func(dir string) {
+				select {
+				case dirChannel <- dir:
+				case <-done:
+					pending.Done()
+				}
+			}(entryPath)
+		}
+
+		select {
+		case files <- FileEntry{path: entryPath, size: info.Size(), mode: info.Mode(), modTime: info.ModTime(), isDir: info.IsDir(), linkTarget: linkTarget}:
+		case <-done:
+			return
 		}
 	}
-	return nil
+}
+
+// symlinkGuard tracks which directory targets have already been descended
+// into via a symlink, so -follow-symlinks can't loop forever on a cyclic
+// link farm. It's shared by every reader goroutine in a single walk.
+type symlinkGuard struct {
+	mu      sync.Mutex
+	visited []os.FileInfo
+}
+
+func newSymlinkGuard() *symlinkGuard {
+	return &symlinkGuard{}
+}
+
+// seen reports whether info's target has already been recorded, and
+// records it if not.
+func (g *symlinkGuard) seen(info os.FileInfo) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, v := range g.visited {
+		if os.SameFile(v, info) {
+			return true
+		}
+	}
+	g.visited = append(g.visited, info)
+	return false
 }
 
 // analyzer processes files from the files channel, analyzes them and then
-// adds them to the database
-func analyzer(files <-chan FileEntry, db *sql.DB, wg *sync.WaitGroup) {
+// writes them to the configured storage backend. Multiple analyzers may
+// run concurrently, each against its own Backend instance - main has
+// already validated cfg and run the backend's one-time schema setup, so
+// analyzer itself never calls Init. When committed is non-nil, a
+// commitAck is sent on it after every Insert attempt (success or
+// failure), so a producer like runIncremental can durably record the
+// entry as seen, or give up tracking it, instead of leaking it forever.
+func analyzer(files <-chan FileEntry, cfg storage.Config, wg *sync.WaitGroup, done <-chan struct{}, committed chan<- commitAck) {
 	defer wg.Done()
 
+	backend, err := storage.Open(backendName, cfg)
+	if err != nil {
+		log.Println("Failed to open storage backend: ", err)
+		return
+	}
+	defer backend.Close()
+
 	magic, err := gomagic.New(gomagic.NoneFlag)
 	if err != nil {
 		return
 	}
 
-	sqlAddEntry := `
-		INSERT INTO file_info (
-			name,
-			size,
-			mode,
-			time,
-			extension,
-			is_dir,
-			short_file_info,
-			file_info
-		) values($1, $2, $3, $4, $5, $6, $7, $8);
-		`
-
-	txCount := 0
-	var tx *sql.Tx
-	var stmt *sql.Stmt
-	for e := range files {
-
-		// prepare new transaction; if it fails we bail for now
-		if txCount == 0 {
-			tx, err = db.Begin()
-			if err != nil {
-				log.Fatal("Failed to prepare transaction")
+loop:
+	for {
+		select {
+		case e, ok := <-files:
+			if !ok {
+				break loop
 			}
-			stmt, err = tx.Prepare(sqlAddEntry)
-			if err != nil {
-				log.Fatal("Failed to open database")
-			}
-		}
 
-		filePath := path.Join(e.path, e.info.Name())
-		if e.info.IsDir() {
-			filePath = e.path
-		}
-		fileExt := path.Ext(e.info.Name())
+			fileExt := filepath.Ext(e.path)
 
-		// strip dot from extension
-		if len(fileExt) != 0 {
-			fileExt = fileExt[1:]
-		}
-
-		fileInfo, err := magic.ExamineFile(filePath)
-		if err != nil {
-			log.Println("gomagic failed on ", filePath, " with", err)
-		}
+			// strip dot from extension
+			if len(fileExt) != 0 {
+				fileExt = fileExt[1:]
+			}
 
-		shortFileInfo := ""
-		if fileInfo != "" {
-			shortFileInfo = strings.Split(fileInfo, ",")[0]
-		}
+			fileInfo, err := magic.ExamineFile(e.path)
+			if err != nil {
+				log.Println("gomagic failed on ", e.path, " with", err)
+			}
 
-		if _, err = stmt.Exec(filePath, e.info.Size(), e.info.Mode(), e.info.ModTime(), fileExt,
-			e.info.IsDir(), shortFileInfo, fileInfo); err != nil {
-			log.Printf("Failed to %s insert transaction into database with: %s\n", filePath, err)
-		}
+			shortFileInfo := ""
+			if fileInfo != "" {
+				shortFileInfo = strings.Split(fileInfo, ",")[0]
+			}
 
-		txCount++
-		if txCount == txCommitInterval {
-			tx.Commit()
-			stmt.Close()
-			txCount = 0
+			entry := storage.FileEntry{Path: e.path, Size: e.size, Mode: e.mode, ModTime: e.modTime, IsDir: e.isDir, LinkTarget: e.linkTarget}
+			analysis := storage.Analysis{
+				Extension:     fileExt,
+				ShortFileInfo: shortFileInfo,
+				FileInfo:      fileInfo,
+				Sha1:          e.hashes["sha1"],
+				Sha256:        e.hashes["sha256"],
+				Blake3:        e.hashes["blake3"],
+			}
+			err = backend.Insert(context.Background(), entry, analysis)
+			if err != nil {
+				log.Printf("Failed to insert %s into storage backend: %s\n", e.path, err)
+			}
+			if committed != nil {
+				committed <- commitAck{path: e.path, fileInfo: fileInfo, ok: err == nil}
+			}
+		case <-done:
+			break loop
 		}
 	}
 
-	// make sure to commit the last transaction in flight
-	if txCount != 0 {
-		tx.Commit()
+	if err := backend.Flush(); err != nil {
+		log.Println("Failed to flush storage backend: ", err)
 	}
 }
 
 // Usage prints the a quick info on how to use the client
 func Usage() {
 	fmt.Println("Usage: calxy -h <host> -n <dbname> -p <dbpass> -u <dbuser> file_tree_root")
+	fmt.Println("       calxy -stdin [-0] < paths.txt")
 	flag.PrintDefaults()
 	os.Exit(1)
 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestSplitNUL(t *testing.T) {
+	input := "a\x00bb\x00\x00ccc"
+	scanner := bufio.NewScanner(bytes.NewBufferString(input))
+	scanner.Split(splitNUL)
+
+	var got []string
+	for scanner.Scan() {
+		got = append(got, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"a", "bb", "", "ccc"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestSymlinkGuardDetectsRepeats(t *testing.T) {
+	dir := t.TempDir()
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := newSymlinkGuard()
+	if g.seen(info) {
+		t.Fatal("first sighting of a target should not be reported as seen")
+	}
+	if !g.seen(info) {
+		t.Fatal("second sighting of the same target should be reported as seen")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/zeebo/blake3"
+)
+
+// hashAlgorithms are the content digests -hash can request.
+var hashAlgorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"blake3": func() hash.Hash { return blake3.New() },
+}
+
+// parseHashAlgorithms splits and validates a comma-separated -hash value,
+// e.g. "sha1,sha256". An empty spec disables hashing.
+func parseHashAlgorithms(spec string) ([]string, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	var algos []string
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := hashAlgorithms[name]; !ok {
+			return nil, fmt.Errorf("unknown hash algorithm %q", name)
+		}
+		algos = append(algos, name)
+	}
+	return algos, nil
+}
+
+// hashStage sits between the walker and the analyzer pool: a bounded pool
+// of numHashers goroutines reads entries from in, computes the requested
+// digests for plain files up to maxHashSize bytes, and forwards the
+// (possibly enriched) entry to out. Directories, symlinks, and files over
+// maxHashSize pass through with no hashes set.
+func hashStage(in <-chan FileEntry, out chan<- FileEntry, numHashers int, algos []string, maxHashSize int64, done <-chan struct{}) {
+	if len(algos) == 0 {
+		go func() {
+			defer close(out)
+			for e := range in {
+				select {
+				case out <- e:
+				case <-done:
+					return
+				}
+			}
+		}()
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < numHashers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range in {
+				e.hashes = hashFile(e, algos, maxHashSize)
+				select {
+				case out <- e:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+}
+
+// hashFile computes algos over the content of e, skipping anything that
+// isn't a regular file (directories, symlinks, devices, pipes, ...) and
+// files larger than maxHashSize (when set).
+func hashFile(e FileEntry, algos []string, maxHashSize int64) map[string]string {
+	if !e.mode.IsRegular() {
+		return nil
+	}
+	if maxHashSize > 0 && e.size > maxHashSize {
+		return nil
+	}
+
+	f, err := os.Open(e.path)
+	if err != nil {
+		log.Println("hashStage: failed to open ", e.path, ": ", err)
+		return nil
+	}
+	defer f.Close()
+
+	hashers := make(map[string]hash.Hash, len(algos))
+	writers := make([]io.Writer, 0, len(algos))
+	for _, name := range algos {
+		h := hashAlgorithms[name]()
+		hashers[name] = h
+		writers = append(writers, h)
+	}
+
+	if _, err := io.Copy(io.MultiWriter(writers...), f); err != nil {
+		log.Println("hashStage: failed to hash ", e.path, ": ", err)
+		return nil
+	}
+
+	digests := make(map[string]string, len(algos))
+	for name, h := range hashers {
+		digests[name] = fmt.Sprintf("%x", h.Sum(nil))
+	}
+	return digests
+}
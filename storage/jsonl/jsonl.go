@@ -0,0 +1,150 @@
+// Package jsonl implements the calyx storage.Backend as a stream of
+// newline-delimited JSON records, written to stdout or a file. Useful
+// for piping calyx's output into jq, other tooling, or benchmarking the
+// walker/analyzer pipeline without a database in the loop.
+package jsonl
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gopher/calyx/storage"
+)
+
+func init() {
+	storage.Register("jsonl", open)
+}
+
+type record struct {
+	Name          string `json:"name"`
+	Size          int64  `json:"size"`
+	Mode          uint32 `json:"mode"`
+	Time          string `json:"time"`
+	Extension     string `json:"extension"`
+	IsDir         bool   `json:"is_dir"`
+	ShortFileInfo string `json:"short_file_info"`
+	FileInfo      string `json:"file_info"`
+	Sha1          string `json:"sha1,omitempty"`
+	Sha256        string `json:"sha256,omitempty"`
+	Blake3        string `json:"blake3,omitempty"`
+	LinkTarget    string `json:"link_target,omitempty"`
+}
+
+// sink is the single underlying writer for a given destination (stdout or
+// a file path). Every Insert, regardless of which analyzer goroutine it
+// came from, goes through sink.mu, so records from concurrent backend
+// instances can never interleave mid-line. refs tracks how many open
+// backends share it, so Close only tears it down once the last one is
+// done with it.
+type sink struct {
+	mu     sync.Mutex
+	file   *os.File
+	w      *bufio.Writer
+	enc    *json.Encoder
+	stdout bool
+	refs   int
+}
+
+var (
+	sinksMu sync.Mutex
+	sinks   = map[string]*sink{}
+)
+
+// sinkKey returns the map key a Config resolves to: distinct file paths
+// get distinct sinks, everything else (empty path or "-") shares stdout.
+func sinkKey(cfg storage.Config) string {
+	if cfg.Path == "" || cfg.Path == "-" {
+		return "-"
+	}
+	return cfg.Path
+}
+
+type backend struct {
+	key string
+	s   *sink
+}
+
+func open(cfg storage.Config) (storage.Backend, error) {
+	key := sinkKey(cfg)
+
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+
+	s, ok := sinks[key]
+	if !ok {
+		var f *os.File
+		stdout := key == "-"
+		if stdout {
+			f = os.Stdout
+		} else {
+			var err error
+			f, err = os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return nil, err
+			}
+		}
+		w := bufio.NewWriter(f)
+		s = &sink{file: f, w: w, enc: json.NewEncoder(w), stdout: stdout}
+		sinks[key] = s
+	}
+	s.refs++
+
+	return &backend{key: key, s: s}, nil
+}
+
+func (b *backend) Init() error {
+	return nil
+}
+
+func (b *backend) Insert(ctx context.Context, entry storage.FileEntry, analysis storage.Analysis) error {
+	b.s.mu.Lock()
+	defer b.s.mu.Unlock()
+	return b.s.enc.Encode(record{
+		Name:          entry.Path,
+		Size:          entry.Size,
+		Mode:          uint32(entry.Mode),
+		Time:          entry.ModTime.Format(time.RFC3339Nano),
+		Extension:     analysis.Extension,
+		IsDir:         entry.IsDir,
+		ShortFileInfo: analysis.ShortFileInfo,
+		FileInfo:      analysis.FileInfo,
+		Sha1:          analysis.Sha1,
+		Sha256:        analysis.Sha256,
+		Blake3:        analysis.Blake3,
+		LinkTarget:    entry.LinkTarget,
+	})
+}
+
+func (b *backend) Flush() error {
+	b.s.mu.Lock()
+	defer b.s.mu.Unlock()
+	return b.s.w.Flush()
+}
+
+// Close flushes the shared sink and, once every backend sharing it has
+// closed, releases the underlying file.
+func (b *backend) Close() error {
+	b.s.mu.Lock()
+	err := b.s.w.Flush()
+	b.s.mu.Unlock()
+
+	sinksMu.Lock()
+	b.s.refs--
+	last := b.s.refs == 0
+	if last {
+		delete(sinks, b.key)
+	}
+	sinksMu.Unlock()
+
+	if !last || b.s.stdout {
+		return err
+	}
+	if cerr := b.s.file.Close(); cerr != nil {
+		return cerr
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package jsonl
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/gopher/calyx/storage"
+)
+
+// TestConcurrentBackendsShareOneSink reproduces the scenario where
+// multiple analyzer goroutines each Open their own jsonl backend against
+// the same output file: every line written through any of them must
+// still be valid, un-interleaved JSON.
+func TestConcurrentBackendsShareOneSink(t *testing.T) {
+	path := t.TempDir() + "/out.jsonl"
+	cfg := storage.Config{Path: path}
+
+	const numBackends = 8
+	const entriesPerBackend = 50
+
+	var wg sync.WaitGroup
+	for i := 0; i < numBackends; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			b, err := storage.Open("jsonl", cfg)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer b.Close()
+			for j := 0; j < entriesPerBackend; j++ {
+				entry := storage.FileEntry{Path: fmt.Sprintf("/tmp/f%d-%d", i, j)}
+				if err := b.Insert(context.Background(), entry, storage.Analysis{}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var lines, seen int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines++
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("line %d is not valid JSON: %v\nline: %s", lines, err, scanner.Text())
+		}
+		seen++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := numBackends * entriesPerBackend; seen != want {
+		t.Fatalf("got %d valid records, want %d", seen, want)
+	}
+}
+
+// TestSinkReusedPerDestination checks that two Opens against the same
+// path share a sink, and distinct paths (including stdout's "-") don't.
+func TestSinkReusedPerDestination(t *testing.T) {
+	path := t.TempDir() + "/out.jsonl"
+
+	a, err := storage.Open("jsonl", storage.Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := storage.Open("jsonl", storage.Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if a.(*backend).s != b.(*backend).s {
+		t.Fatal("backends opened against the same path should share a sink")
+	}
+
+	// Deliberately not Close()'d: it shares the stdout sink, which this
+	// test must not tear down.
+	c, err := storage.Open("jsonl", storage.Config{Path: "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.(*backend).s == c.(*backend).s {
+		t.Fatal("backends opened against different destinations should not share a sink")
+	}
+}
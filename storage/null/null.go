@@ -0,0 +1,27 @@
+// Package null implements a no-op calyx storage.Backend, for benchmarking
+// the walker/analyzer pipeline in isolation from any storage cost.
+package null
+
+import (
+	"context"
+
+	"github.com/gopher/calyx/storage"
+)
+
+func init() {
+	storage.Register("null", open)
+}
+
+type backend struct{}
+
+func open(cfg storage.Config) (storage.Backend, error) {
+	return backend{}, nil
+}
+
+func (backend) Init() error { return nil }
+
+func (backend) Insert(context.Context, storage.FileEntry, storage.Analysis) error { return nil }
+
+func (backend) Flush() error { return nil }
+
+func (backend) Close() error { return nil }
@@ -0,0 +1,122 @@
+// Package sqlite implements the calyx storage.Backend on top of an
+// embedded SQLite file, for single-user local scans that don't warrant
+// standing up Postgres.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/gopher/calyx/storage"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const txCommitInterval = 1000
+const defaultPath = "calyx.db"
+
+func init() {
+	storage.Register("sqlite", open)
+}
+
+type backend struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	stmt    *sql.Stmt
+	txCount int
+}
+
+func open(cfg storage.Config) (storage.Backend, error) {
+	path := cfg.Path
+	if path == "" {
+		path = defaultPath
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	return &backend{db: db}, nil
+}
+
+func (b *backend) Init() error {
+	sqlStmt := `
+	CREATE TABLE IF NOT EXISTS file_info (
+		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		name TEXT,
+		size BIGINT,
+		mode BIGINT,
+		time TEXT,
+		extension TEXT,
+		is_dir BOOLEAN,
+		short_file_info TEXT,
+		file_info TEXT,
+		sha1 TEXT,
+		sha256 TEXT,
+		blake3 TEXT,
+		link_target TEXT);
+	CREATE UNIQUE INDEX IF NOT EXISTS file_info_name_key ON file_info (name);
+	`
+	_, err := b.db.Exec(sqlStmt)
+	return err
+}
+
+var sqlAddEntry = `
+	INSERT INTO file_info (
+		name, size, mode, time, extension, is_dir, short_file_info, file_info, sha1, sha256, blake3, link_target
+	) values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(name) DO UPDATE SET
+		size = excluded.size,
+		mode = excluded.mode,
+		time = excluded.time,
+		extension = excluded.extension,
+		is_dir = excluded.is_dir,
+		short_file_info = excluded.short_file_info,
+		file_info = excluded.file_info,
+		sha1 = excluded.sha1,
+		sha256 = excluded.sha256,
+		blake3 = excluded.blake3,
+		link_target = excluded.link_target;
+	`
+
+func (b *backend) Insert(ctx context.Context, entry storage.FileEntry, analysis storage.Analysis) error {
+	var err error
+	if b.txCount == 0 {
+		b.tx, err = b.db.Begin()
+		if err != nil {
+			return err
+		}
+		b.stmt, err = b.tx.Prepare(sqlAddEntry)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err = b.stmt.Exec(entry.Path, entry.Size, entry.Mode, entry.ModTime, analysis.Extension,
+		entry.IsDir, analysis.ShortFileInfo, analysis.FileInfo, analysis.Sha1, analysis.Sha256,
+		analysis.Blake3, entry.LinkTarget); err != nil {
+		return err
+	}
+
+	b.txCount++
+	if b.txCount == txCommitInterval {
+		if err := b.tx.Commit(); err != nil {
+			return err
+		}
+		b.stmt.Close()
+		b.txCount = 0
+	}
+	return nil
+}
+
+func (b *backend) Flush() error {
+	if b.txCount == 0 {
+		return nil
+	}
+	err := b.tx.Commit()
+	b.stmt.Close()
+	b.txCount = 0
+	return err
+}
+
+func (b *backend) Close() error {
+	return b.db.Close()
+}
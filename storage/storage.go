@@ -0,0 +1,85 @@
+// Package storage abstracts the destination calyx writes analyzed file
+// entries to, so the walker/analyzer pipeline doesn't need to know
+// whether it's talking to Postgres, a local SQLite file, an NDJSON
+// stream, or nothing at all.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// FileEntry is the stat information for a single filesystem entry.
+type FileEntry struct {
+	Path    string
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
+	IsDir   bool
+	// LinkTarget is the symlink target, as returned by os.Readlink, for
+	// entries with the os.ModeSymlink bit set in Mode. Empty otherwise.
+	LinkTarget string
+}
+
+// Analysis is the result of running a FileEntry through the magic
+// analyzer and, optionally, the content hashing stage.
+type Analysis struct {
+	Extension     string
+	ShortFileInfo string
+	FileInfo      string
+	Sha1          string
+	Sha256        string
+	Blake3        string
+}
+
+// Backend is a destination calyx can write analyzed file entries to.
+// main opens one Backend per analyzer goroutine, so every method must be
+// safe to call concurrently from multiple Backend instances that were
+// Open'd with the same Config - a backend writing to a shared sink (a
+// single file, a single connection) is responsible for synchronizing
+// those instances itself, e.g. by sharing an internal writer behind a
+// mutex, the way the jsonl backend does.
+type Backend interface {
+	// Init performs the destination's one-time setup (e.g. creating
+	// tables). main calls it once, on an instance of its own, before any
+	// instance is used for Insert; instances that Insert are used as Open
+	// returned them, without Init.
+	Init() error
+	// Insert records a single analyzed entry.
+	Insert(ctx context.Context, entry FileEntry, analysis Analysis) error
+	// Flush commits any writes buffered by the backend.
+	Flush() error
+	// Close releases any resources held by the backend.
+	Close() error
+}
+
+// Config carries the command-line settings a backend may need in order
+// to open itself.
+type Config struct {
+	DBUser string
+	DBName string
+	DBPass string
+	DBHost string
+	Path   string // sqlite file path, or jsonl output path ("-" for stdout)
+}
+
+type factory func(Config) (Backend, error)
+
+var backends = map[string]factory{}
+
+// Register makes a backend constructor available under name. It's meant
+// to be called from the init() of a backend's package.
+func Register(name string, f factory) {
+	backends[name] = f
+}
+
+// Open constructs the backend registered under name with cfg.
+func Open(name string, cfg Config) (Backend, error) {
+	f, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("storage: unknown backend %q", name)
+	}
+	return f(cfg)
+}